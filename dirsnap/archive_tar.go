@@ -0,0 +1,78 @@
+package dirsnap
+
+import (
+	"archive/tar"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ReadTar reads a tar archive from r and returns a Dirs tree of its
+// directory and file entries.
+//
+// Empty directories must be present in the archive as zero-length
+// directory entries (see WriteTar) to be preserved.
+func ReadTar(r io.Reader) (Dirs, error) {
+	d := Dirs{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			d.ensureDir(strings.TrimSuffix(hdr.Name, "/"))
+		case tar.TypeReg:
+			d.ensureFile(hdr.Name)
+		}
+	}
+	return d, nil
+}
+
+// WriteTar writes Dirs d as a tar archive to w, preserving empty
+// directories as zero-length directory entries.
+//
+// Dirs only tracks whether an entry is a file or a directory, not its mode,
+// so entries are written with a fixed Mode (0600 for files, 0700 for
+// directories) rather than round-tripping the original permissions; see
+// filesnap.FilesEx.WriteTar for mode-preserving archives.
+func (d Dirs) WriteTar(w io.Writer) error {
+	tw := tar.NewWriter(w)
+	if err := d.writeTar(tw, ""); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+func (d Dirs) writeTar(tw *tar.Writer, prefix string) error {
+	names := make([]string, 0, len(d))
+	for n := range d {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		p := path.Join(prefix, n)
+		st := d[n]
+		if st == nil {
+			hdr := &tar.Header{Name: p, Typeflag: tar.TypeReg, Mode: 0600}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			continue
+		}
+		hdr := &tar.Header{Name: p + "/", Typeflag: tar.TypeDir, Mode: 0700}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if err := st.writeTar(tw, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
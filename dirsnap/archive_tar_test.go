@@ -0,0 +1,30 @@
+package dirsnap_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/echocrow/fsnap/dirsnap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTreeTarRoundTrip(t *testing.T) {
+	tr := fsd{
+		"emptyDir": fsd{},
+		"some": fsd{
+			"sub": fsd{
+				"dir": fsd{},
+			},
+			"nested.txt": nil,
+		},
+		"anotherFile": nil,
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, tr.WriteTar(&buf))
+
+	got, err := dirsnap.ReadTar(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, tr, got)
+}
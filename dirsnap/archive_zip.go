@@ -0,0 +1,71 @@
+package dirsnap
+
+import (
+	"archive/zip"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ReadZip reads a zip archive of the given size from r and returns a Dirs
+// tree of its directory and file entries.
+//
+// Empty directories must be present in the archive as zero-length entries
+// whose name ends in "/" (see WriteZip) to be preserved.
+func ReadZip(r io.ReaderAt, size int64) (Dirs, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	d := Dirs{}
+	for _, zf := range zr.File {
+		if strings.HasSuffix(zf.Name, "/") {
+			d.ensureDir(strings.TrimSuffix(zf.Name, "/"))
+		} else {
+			d.ensureFile(zf.Name)
+		}
+	}
+	return d, nil
+}
+
+// WriteZip writes Dirs d as a zip archive to w, preserving empty
+// directories as zero-length entries whose name ends in "/".
+//
+// Dirs only tracks whether an entry is a file or a directory, not its mode,
+// so entries are written with the zip package's default mode rather than
+// round-tripping the original permissions; see filesnap.FilesEx.WriteZip for
+// mode-preserving archives.
+func (d Dirs) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	if err := d.writeZip(zw, ""); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func (d Dirs) writeZip(zw *zip.Writer, prefix string) error {
+	names := make([]string, 0, len(d))
+	for n := range d {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		p := path.Join(prefix, n)
+		st := d[n]
+		if st == nil {
+			if _, err := zw.Create(p); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := zw.Create(p + "/"); err != nil {
+			return err
+		}
+		if err := st.writeZip(zw, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
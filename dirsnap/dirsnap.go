@@ -44,9 +44,15 @@
 package dirsnap
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
 	"io/fs"
 	"path"
+	"sort"
+	"strings"
 
 	os "github.com/echocrow/osa"
 )
@@ -62,59 +68,207 @@ type Dirs map[string]Dirs
 // If n < 0, Read will scan all subdirectories.
 //
 // If n >= 0, Read will descend at most n directory levels below directory dir.
-func Read(dir string, n int) (Dirs, error) {
+func Read(dir string, n int, opts ...ReadOpt) (Dirs, error) {
 	osa := os.Current()
-	return ReadFS(osa, dir, n)
+	return ReadFS(osa, dir, n, opts...)
 }
 
 // ReadFS scans a fsys directory dir and returs a Dirs tree of its files and
 // folders.
 //
 // See Read().
-func ReadFS(fsys fs.FS, dir string, n int) (Dirs, error) {
+func ReadFS(fsys fs.FS, dir string, n int, opts ...ReadOpt) (Dirs, error) {
+	var o readOpts
+	for _, opt := range opts {
+		opt(&o)
+	}
 	t := Dirs{}
+	err := t.readFS(fsys, dir, "", n, &o)
+	return t, err
+}
 
+func (t Dirs) readFS(fsys fs.FS, rootDir, subDir string, n int, o *readOpts) error {
+	dir := path.Join(rootDir, subDir)
 	entries, err := fs.ReadDir(fsys, dir)
 	if err != nil && err != io.EOF {
-		return nil, err
+		return err
 	}
 	for _, e := range entries {
 		en := e.Name()
+		sp := path.Join(subDir, en)
+		if o.Select != nil && !o.Select(sp, e) {
+			continue
+		}
+		if o.isExcluded(sp) {
+			continue
+		}
 		if !e.IsDir() {
+			if !o.isIncluded(sp) {
+				continue
+			}
 			t[en] = nil
 		} else if n == 0 {
 			t[en] = Dirs{}
 		} else {
-			var err error
-			t[en], err = ReadFS(fsys, path.Join(dir, en), n-1)
-			if err != nil {
-				return t, err
+			sub := Dirs{}
+			if err := sub.readFS(fsys, rootDir, sp, n-1, o); err != nil {
+				return err
+			}
+			t[en] = sub
+		}
+	}
+
+	return nil
+}
+
+// Hash returns a stable structural hash for d, derived from a sorted list of
+// (name, kind, childHash) tuples.
+//
+// Two Dirs with the same shape always produce the same hash, independent of
+// map iteration order.
+func (d Dirs) Hash() string {
+	names := make([]string, 0, len(d))
+	for n := range d {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, n := range names {
+		io.WriteString(h, n)
+		h.Write([]byte{0})
+		if st := d[n]; st == nil {
+			h.Write([]byte{'f'})
+		} else {
+			h.Write([]byte{'d'})
+			io.WriteString(h, st.Hash())
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Diff compares two Dirs trees and returns the paths that were added,
+// removed, or changed between a and b.
+//
+// A path is "changed" if it exists in both trees but switched between file
+// and directory. If a and b share the same Hash, Diff short-circuits and
+// returns no differences without walking either tree; the same check
+// applies to every shared subtree, so unchanged branches aren't walked
+// either.
+func Diff(a, b Dirs) (added, removed, changed []string) {
+	if a.Hash() == b.Hash() {
+		return nil, nil, nil
+	}
+	diffTrees(a, b, "", &added, &removed, &changed)
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
+
+func diffTrees(a, b Dirs, prefix string, added, removed, changed *[]string) {
+	for n, bst := range b {
+		p := path.Join(prefix, n)
+		ast, ok := a[n]
+		if !ok {
+			*added = append(*added, p)
+			continue
+		}
+		aIsDir, bIsDir := ast != nil, bst != nil
+		if aIsDir != bIsDir {
+			*changed = append(*changed, p)
+			continue
+		}
+		if bIsDir {
+			if ast.Hash() == bst.Hash() {
+				continue
 			}
+			diffTrees(ast, bst, p, added, removed, changed)
 		}
 	}
+	for n := range a {
+		if _, ok := b[n]; !ok {
+			*removed = append(*removed, path.Join(prefix, n))
+		}
+	}
+}
 
-	return t, nil
+// WriteFS is a writable fs.FS, extending it with the operations Write needs
+// to create files and folders.
+type WriteFS interface {
+	fs.FS
+	MkdirAll(string, fs.FileMode) error
+	WriteFile(string, []byte, fs.FileMode) error
+	Mkdir(string, fs.FileMode) error
+}
+
+// AtomicWriteFS is a WriteFS that can also remove entries, allowing WriteFS
+// to roll back a partially written tree when WriteOpts.Atomic is set.
+type AtomicWriteFS interface {
+	WriteFS
+	Remove(name string) error
+	RemoveAll(name string) error
 }
 
 // Write writes Dirs d into directory dir, creating new files and folders
 // accordingly.
 //
-// Collisions with already existing files or folders will not result in errors
-// as long as they are of the same type (directory or file respectively).
-func (d Dirs) Write(dir string) error {
+// Collisions with already existing files or folders will not result in
+// errors as long as they are of the same type (directory or file
+// respectively), unless WriteOpts.Overwrite is set to false. If
+// WriteOpts.Atomic is set, Write removes every path it created if a later
+// write fails. Entries that already existed are not restored: an existing
+// file that Write rewrote before the failure is left empty.
+func (d Dirs) Write(dir string, opts ...WriteOpt) error {
+	return d.WriteFS(os.Current(), dir, opts...)
+}
+
+// WriteFS writes Dirs d into directory dir of fsys, creating new files and
+// folders accordingly.
+//
+// See Write(). WriteOpts.Atomic requires fsys to implement AtomicWriteFS.
+func (d Dirs) WriteFS(fsys WriteFS, dir string, opts ...WriteOpt) error {
+	o := newWriteOpts(opts)
+	if !o.Atomic {
+		return d.writeFS(fsys, dir, o, nil)
+	}
+	afsys, ok := fsys.(AtomicWriteFS)
+	if !ok {
+		return errors.New("dirsnap: fsys does not support atomic writes")
+	}
+	var created []string
+	if err := d.writeFS(fsys, dir, o, &created); err != nil {
+		for i := len(created) - 1; i >= 0; i-- {
+			_ = afsys.RemoveAll(created[i])
+		}
+		return err
+	}
+	return nil
+}
+
+func (d Dirs) writeFS(fsys WriteFS, dir string, o WriteOpts, created *[]string) error {
 	for n, st := range d {
 		name := path.Join(dir, n)
+		existed := existsFS(fsys, name)
+		if existed && !o.Overwrite {
+			return fmt.Errorf("dirsnap: %s already exists", name)
+		}
 		if st == nil {
 			// Handle file.
-			if err := writeEmptyFile(name); !d.isWriteErrOk(err, name, false) {
+			if err := writeEmptyFileFS(fsys, name); !d.isWriteErrOkFS(fsys, err, name, false) {
 				return err
 			}
 		} else {
 			// Handle dir.
-			if err := os.Mkdir(name, 0700); !d.isWriteErrOk(err, name, true) {
+			if err := fsys.Mkdir(name, 0700); !d.isWriteErrOkFS(fsys, err, name, true) {
 				return err
 			}
-			if err := st.Write(name); err != nil {
+		}
+		if created != nil && !existed {
+			*created = append(*created, name)
+		}
+		if st != nil {
+			if err := st.writeFS(fsys, name, o, created); err != nil {
 				return err
 			}
 		}
@@ -122,15 +276,46 @@ func (d Dirs) Write(dir string) error {
 	return nil
 }
 
-func (t Dirs) isWriteErrOk(err error, name string, wantDir bool) bool {
+func existsFS(fsys WriteFS, name string) bool {
+	_, err := fs.Stat(fsys, name)
+	return err == nil
+}
+
+func (t Dirs) isWriteErrOkFS(fsys WriteFS, err error, name string, wantDir bool) bool {
 	if !os.IsExist(err) {
 		return err == nil
 	}
-	fi, err := os.Stat(name)
+	fi, err := fs.Stat(fsys, name)
 	gotDir := fi != nil && fi.IsDir()
 	return err == nil && gotDir == wantDir
 }
 
-func writeEmptyFile(name string) error {
-	return os.WriteFile(name, []byte{}, 0600)
+func writeEmptyFileFS(fsys WriteFS, name string) error {
+	return fsys.WriteFile(name, []byte{}, 0600)
+}
+
+// ensureDir returns the Dirs for the nested path p below d, creating any
+// missing directories along the way.
+func (d Dirs) ensureDir(p string) Dirs {
+	p = path.Clean(p)
+	if p == "" || p == "." {
+		return d
+	}
+	cur := d
+	for _, part := range strings.Split(p, "/") {
+		sub, ok := cur[part]
+		if !ok || sub == nil {
+			sub = Dirs{}
+			cur[part] = sub
+		}
+		cur = sub
+	}
+	return cur
+}
+
+// ensureFile adds a file entry at the nested path p below d, creating any
+// missing parent directories along the way.
+func (d Dirs) ensureFile(p string) {
+	dir, name := path.Split(path.Clean(p))
+	d.ensureDir(strings.TrimSuffix(dir, "/"))[name] = nil
 }
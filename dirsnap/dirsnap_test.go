@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/echocrow/fsnap/dirsnap"
+	"github.com/echocrow/fsnap/memfs"
 	"github.com/echocrow/osa"
 	tos "github.com/echocrow/osa/testos"
 	"github.com/echocrow/osa/vos"
@@ -100,7 +101,9 @@ func testScanTree(
 func TestScanTree(t *testing.T) {
 	os, reset := vos.Patch()
 	defer reset()
-	testScanTree(t, os, dirsnap.Read)
+	testScanTree(t, os, func(name string, n int) (fsd, error) {
+		return dirsnap.Read(name, n)
+	})
 }
 
 func TestScanFSTree(t *testing.T) {
@@ -171,6 +174,153 @@ func TestWriteTree(t *testing.T) {
 		err := tr.Write(tmpDir)
 		assert.Error(t, err)
 	})
+
+	t.Run("ErrOverwriteDisabled", func(t *testing.T) {
+		tmpDir := tos.RequireTempDir(t, os)
+		n := "existingFile"
+		tos.RequireEmptyWrite(t, os, tos.Join(tmpDir, n))
+
+		tr := fsd{n: nil}
+		err := tr.Write(tmpDir, dirsnap.WithOverwrite(false))
+		assert.Error(t, err)
+	})
+
+	t.Run("AtomicRollsBackOnError", func(t *testing.T) {
+		tmpDir := tos.RequireTempDir(t, os)
+		n := "collFile"
+		tos.RequireEmptyWrite(t, os, tos.Join(tmpDir, n))
+
+		tr := fsd{
+			"newDir": fsd{},
+			n:        fsd{},
+		}
+		err := tr.Write(tmpDir, dirsnap.WithAtomic(true))
+		assert.Error(t, err)
+
+		_, statErr := os.Stat(tos.Join(tmpDir, "newDir"))
+		assert.True(t, os.IsNotExist(statErr), "want rolled-back dir to not exist")
+	})
+}
+
+func TestScanTreeFilter(t *testing.T) {
+	os, reset := vos.Patch()
+	defer reset()
+
+	tmpDir := tos.RequireTempDir(t, os)
+	tos.RequireEmptyWrite(t, os, tos.Join(tmpDir, "a.txt"))
+	tos.RequireEmptyWrite(t, os, tos.Join(tmpDir, "a.log"))
+	tos.RequireMkdirAll(t, os, tos.Join(tmpDir, "node_modules"))
+	tos.RequireEmptyWrite(t, os, tos.Join(tmpDir, "node_modules", "dep.js"))
+
+	t.Run("Include", func(t *testing.T) {
+		got, err := dirsnap.Read(tmpDir, -1, dirsnap.WithInclude("**/*.txt"))
+		assert.NoError(t, err)
+		assert.Equal(t, fsd{
+			"a.txt":        nil,
+			"node_modules": fsd{},
+		}, got)
+	})
+
+	t.Run("Exclude", func(t *testing.T) {
+		got, err := dirsnap.Read(tmpDir, -1, dirsnap.WithExclude("node_modules/"))
+		assert.NoError(t, err)
+		assert.Equal(t, fsd{
+			"a.txt": nil,
+			"a.log": nil,
+		}, got)
+	})
+}
+
+func TestWriteFSTree(t *testing.T) {
+	fsys := memfs.New()
+
+	tr := fsd{
+		"emptyDir": fsd{},
+		"some": fsd{
+			"nested.txt": nil,
+		},
+	}
+
+	err := tr.WriteFS(fsys, ".")
+	assert.NoError(t, err)
+
+	got, err := dirsnap.ReadFS(fsys, ".", -1)
+	assert.NoError(t, err)
+	assert.Equal(t, tr, got)
+}
+
+func TestTreeHash(t *testing.T) {
+	a := fsd{
+		"dir":   fsd{"file": nil},
+		"other": nil,
+	}
+	b := fsd{
+		"other": nil,
+		"dir":   fsd{"file": nil},
+	}
+	c := fsd{
+		"dir":   fsd{"file": nil, "extra": nil},
+		"other": nil,
+	}
+
+	assert.Equal(t, a.Hash(), b.Hash(), "want map order to not affect hash")
+	assert.NotEqual(t, a.Hash(), c.Hash(), "want differing shapes to produce differing hashes")
+}
+
+func TestTreeDiff(t *testing.T) {
+	tests := []struct {
+		name        string
+		a, b        fsd
+		wantAdded   []string
+		wantRemoved []string
+		wantChanged []string
+	}{
+		{
+			"Equal",
+			fsd{"a": nil}, fsd{"a": nil},
+			nil, nil, nil,
+		},
+		{
+			"Added",
+			fsd{}, fsd{"a": nil},
+			[]string{"a"}, nil, nil,
+		},
+		{
+			"Removed",
+			fsd{"a": nil}, fsd{},
+			nil, []string{"a"}, nil,
+		},
+		{
+			"KindChanged",
+			fsd{"a": nil}, fsd{"a": fsd{}},
+			nil, nil, []string{"a"},
+		},
+		{
+			"Nested",
+			fsd{"dir": fsd{"a": nil}}, fsd{"dir": fsd{"b": nil}},
+			[]string{"dir/b"}, []string{"dir/a"}, nil,
+		},
+		{
+			"UnchangedSubtreeSkipped",
+			fsd{
+				"same":     fsd{"a": nil},
+				"changing": fsd{"a": nil},
+			},
+			fsd{
+				"same":     fsd{"a": nil},
+				"changing": fsd{"b": nil},
+			},
+			[]string{"changing/b"}, []string{"changing/a"}, nil,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			added, removed, changed := dirsnap.Diff(tc.a, tc.b)
+			assert.Equal(t, tc.wantAdded, added, "added")
+			assert.Equal(t, tc.wantRemoved, removed, "removed")
+			assert.Equal(t, tc.wantChanged, changed, "changed")
+		})
+	}
 }
 
 func TestWriteTreeScanTree(t *testing.T) {
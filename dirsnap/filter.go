@@ -0,0 +1,97 @@
+package dirsnap
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// ReadOpt configures Read and ReadFS.
+type ReadOpt func(*readOpts)
+
+type readOpts struct {
+	Select  func(path string, d fs.DirEntry) bool
+	Include []string
+	Exclude []string
+}
+
+// WithSelect adds a predicate that controls whether a path is included in
+// the tree. Returning false for a directory prunes the walk: Read will not
+// descend into it.
+func WithSelect(sel func(path string, d fs.DirEntry) bool) ReadOpt {
+	return func(o *readOpts) { o.Select = sel }
+}
+
+// WithInclude restricts the tree to files matching one of the given
+// doublestar-style glob patterns (e.g. "**/*.log"). Directories are always
+// traversed, since a directory not matching a pattern may still contain
+// files that do.
+func WithInclude(patterns ...string) ReadOpt {
+	return func(o *readOpts) { o.Include = patterns }
+}
+
+// WithExclude prunes paths matching one of the given doublestar-style glob
+// patterns (e.g. "node_modules/") from the tree. Excluding a directory
+// prunes the walk: Read will not descend into it.
+func WithExclude(patterns ...string) ReadOpt {
+	return func(o *readOpts) { o.Exclude = patterns }
+}
+
+func (o *readOpts) isIncluded(p string) bool {
+	if len(o.Include) == 0 {
+		return true
+	}
+	for _, pat := range o.Include {
+		if matchGlob(pat, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *readOpts) isExcluded(p string) bool {
+	for _, pat := range o.Exclude {
+		if matchGlob(pat, p) || matchGlob(pat+"/**", p) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob reports whether name matches pattern, where "**" matches any
+// number of path segments (including none) and "*" matches within a single
+// segment, mirroring doublestar-style globs.
+func matchGlob(pattern, name string) bool {
+	return matchGlobParts(splitPath(pattern), splitPath(name))
+}
+
+func splitPath(p string) []string {
+	p = path.Clean(p)
+	if p == "." || p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+func matchGlobParts(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		if matchGlobParts(pattern[1:], name) {
+			return true
+		}
+		if len(name) == 0 {
+			return false
+		}
+		return matchGlobParts(pattern, name[1:])
+	}
+	if len(name) == 0 {
+		return false
+	}
+	ok, err := path.Match(pattern[0], name[0])
+	if err != nil || !ok {
+		return false
+	}
+	return matchGlobParts(pattern[1:], name[1:])
+}
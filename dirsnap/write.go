@@ -0,0 +1,33 @@
+package dirsnap
+
+// WriteOpts configures Write and WriteFS.
+type WriteOpts struct {
+	// Atomic removes every path this call created if a later write fails.
+	// Entries that already existed are not restored.
+	Atomic bool
+	// Overwrite allows Write to reuse an already existing file or
+	// directory of the same kind. When false, any existing entry at a
+	// target path results in an error.
+	Overwrite bool
+}
+
+// WriteOpt sets an option on WriteOpts.
+type WriteOpt func(*WriteOpts)
+
+// WithAtomic sets WriteOpts.Atomic.
+func WithAtomic(atomic bool) WriteOpt {
+	return func(o *WriteOpts) { o.Atomic = atomic }
+}
+
+// WithOverwrite sets WriteOpts.Overwrite.
+func WithOverwrite(overwrite bool) WriteOpt {
+	return func(o *WriteOpts) { o.Overwrite = overwrite }
+}
+
+func newWriteOpts(opts []WriteOpt) WriteOpts {
+	o := WriteOpts{Overwrite: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
@@ -0,0 +1,103 @@
+package filesnap
+
+import (
+	"archive/tar"
+	"io"
+	"io/fs"
+)
+
+// ReadTar reads a tar archive from r and returns its Files.
+//
+// Non-regular entries (directories, symlinks, etc.) are ignored.
+func ReadTar(r io.Reader) (Files, error) {
+	f := Files{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		f[hdr.Name] = data
+	}
+	return f, nil
+}
+
+// ReadTarEx reads a tar archive from r and returns its FilesEx, recording
+// each entry's Mode.
+//
+// Non-regular entries (directories, symlinks, etc.) are ignored.
+func ReadTarEx(r io.Reader) (FilesEx, error) {
+	f := FilesEx{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		f[hdr.Name] = File{Data: data, Mode: fs.FileMode(hdr.Mode).Perm()}
+	}
+	return f, nil
+}
+
+// WriteTar writes Files f as a tar archive to w.
+func (f Files) WriteTar(w io.Writer) error {
+	tw := tar.NewWriter(w)
+	for n, data := range f {
+		hdr := &tar.Header{
+			Name: n,
+			Mode: 0600,
+			Size: int64(len(data)),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// WriteTar writes FilesEx f as a tar archive to w, preserving each file's
+// Mode so it round-trips through ReadTarEx. A zero Mode is written as 0600.
+func (f FilesEx) WriteTar(w io.Writer) error {
+	tw := tar.NewWriter(w)
+	for n, file := range f {
+		mode := file.Mode
+		if mode == 0 {
+			mode = 0600
+		}
+		hdr := &tar.Header{
+			Name: n,
+			Mode: int64(mode.Perm()),
+			Size: int64(len(file.Data)),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(file.Data); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
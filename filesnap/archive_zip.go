@@ -0,0 +1,100 @@
+package filesnap
+
+import (
+	"archive/zip"
+	"io"
+)
+
+// ReadZip reads a zip archive of the given size from r and returns its
+// Files.
+//
+// Directory entries in the archive are ignored.
+func ReadZip(r io.ReaderAt, size int64) (Files, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	f := Files{}
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		f[zf.Name] = data
+	}
+	return f, nil
+}
+
+// ReadZipEx reads a zip archive of the given size from r and returns its
+// FilesEx, recording each entry's Mode.
+//
+// Directory entries in the archive are ignored.
+func ReadZipEx(r io.ReaderAt, size int64) (FilesEx, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	f := FilesEx{}
+	for _, zf := range zr.File {
+		fi := zf.FileInfo()
+		if fi.IsDir() {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		f[zf.Name] = File{Data: data, Mode: fi.Mode().Perm()}
+	}
+	return f, nil
+}
+
+// WriteZip writes Files f as a zip archive to w.
+func (f Files) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for n, data := range f {
+		fw, err := zw.Create(n)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// WriteZip writes FilesEx f as a zip archive to w, preserving each file's
+// Mode so it round-trips through ReadZipEx. A zero Mode is written as 0600.
+func (f FilesEx) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for n, file := range f {
+		mode := file.Mode
+		if mode == 0 {
+			mode = 0600
+		}
+		fh := &zip.FileHeader{Name: n, Method: zip.Deflate}
+		fh.SetMode(mode)
+		fw, err := zw.CreateHeader(fh)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(file.Data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,39 @@
+package filesnap_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/echocrow/fsnap/filesnap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilesZipRoundTrip(t *testing.T) {
+	f := fsf{
+		"myFile":      []byte("some contents"),
+		"emptyFile":   []byte{},
+		"nested/file": []byte("foobar"),
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, f.WriteZip(&buf))
+
+	got, err := filesnap.ReadZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.Equal(t, f, got)
+}
+
+func TestFilesExZipRoundTripPreservesMode(t *testing.T) {
+	f := filesnap.FilesEx{
+		"myFile":      {Data: []byte("some contents"), Mode: 0640},
+		"nested/file": {Data: []byte("foobar"), Mode: 0755},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, f.WriteZip(&buf))
+
+	got, err := filesnap.ReadZipEx(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.Equal(t, f, got)
+}
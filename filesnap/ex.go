@@ -0,0 +1,210 @@
+package filesnap
+
+import (
+	"io"
+	"io/fs"
+	stdos "os"
+	"path"
+	"time"
+
+	os "github.com/echocrow/osa"
+)
+
+// File represents a single file entry of a FilesEx snapshot.
+//
+// LinkTarget is only set for symlinks (see ReadOpts.FollowSymlinks); in that
+// case Data is unset, Mode is fs.ModeSymlink, and the entry is recreated as a
+// symlink pointing at LinkTarget rather than as a regular file.
+type File struct {
+	Data       []byte
+	Mode       fs.FileMode
+	ModTime    time.Time
+	LinkTarget string
+}
+
+// FilesEx represents a snapshot of files in a directory and its
+// subdirectories, like Files, but additionally capable of carrying each
+// file's mode, modification time, and symlink target.
+//
+// Keys represent the subpath of the files and their values are the
+// respective File entries.
+type FilesEx map[string]File
+
+// ReadOpts configures ReadEx and ReadExFS.
+type ReadOpts struct {
+	// WithMode records each file's fs.FileMode on its File entry.
+	WithMode bool
+	// WithModTime records each file's modification time on its File entry.
+	WithModTime bool
+	// FollowSymlinks dereferences symlinks and snapshots their target's
+	// contents. When false (the default), symlinks are recorded as File
+	// entries with a LinkTarget instead of being followed.
+	FollowSymlinks bool
+}
+
+// ReadLinkFS is implemented by file systems that can report a symlink's
+// target without following it, such as the OS filesystem.
+//
+// fsys implementations that don't implement ReadLinkFS (such as memfs.FS)
+// can't report link targets; readFS falls back to reading a symlink's
+// dereferenced contents like a regular file in that case.
+type ReadLinkFS interface {
+	fs.FS
+	ReadLink(name string) (string, error)
+}
+
+// ReadEx scans a directory and returns its FilesEx.
+//
+// If n < 0, ReadEx will scan all subdirectories.
+//
+// If n >= 0, ReadEx will descend at most n directory levels below the given
+// directory.
+func ReadEx(dir string, n int, opts ReadOpts) (FilesEx, error) {
+	osa := os.Current()
+	return ReadExFS(osa, dir, n, opts)
+}
+
+// ReadExFS scans a fsys directory and returns its FilesEx.
+//
+// See ReadEx().
+func ReadExFS(fsys fs.FS, dir string, n int, opts ReadOpts) (FilesEx, error) {
+	f := FilesEx{}
+	err := f.readFS(fsys, dir, "", n, opts)
+	return f, err
+}
+
+func (f FilesEx) readFS(fsys fs.FS, rootDir, subDir string, n int, opts ReadOpts) error {
+	dir := path.Join(rootDir, subDir)
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	for _, e := range entries {
+		p := path.Join(dir, e.Name())
+		sp := path.Join(subDir, e.Name())
+
+		if e.Type()&fs.ModeSymlink != 0 && !opts.FollowSymlinks {
+			if rl, ok := fsys.(ReadLinkFS); ok {
+				target, err := rl.ReadLink(p)
+				if err != nil {
+					return err
+				}
+				f[sp] = File{LinkTarget: target, Mode: e.Type()}
+				continue
+			}
+			// fsys can't report the link target; fall through and read
+			// the symlink's dereferenced contents like a regular file.
+		}
+
+		if !e.IsDir() {
+			data, err := fs.ReadFile(fsys, p)
+			if err != nil && err != io.EOF {
+				return err
+			}
+			file := File{Data: data}
+			if opts.WithMode || opts.WithModTime {
+				fi, err := e.Info()
+				if err != nil {
+					return err
+				}
+				if opts.WithMode {
+					file.Mode = fi.Mode()
+				}
+				if opts.WithModTime {
+					file.ModTime = fi.ModTime()
+				}
+			}
+			f[sp] = file
+		} else if n != 0 {
+			if err := f.readFS(fsys, rootDir, sp, n-1, opts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ExWriteFS is a WriteFS, extending it with the operations FilesEx.WriteFS
+// needs to recreate a File's Mode, ModTime, and LinkTarget. Since none of
+// these have an osa equivalent, ExWriteFS is deliberately not backed by the
+// osa abstraction, so callers can't silently mix it with a mocked os.
+type ExWriteFS interface {
+	WriteFS
+	// Symlink creates name as a symlink pointing at target.
+	Symlink(target, name string) error
+	// Chtimes changes the access and modification times of name.
+	Chtimes(name string, atime, mtime time.Time) error
+}
+
+// realExWriteFS adapts the real OS filesystem to ExWriteFS, since symlinks
+// and modification times can only be written against a real filesystem.
+type realExWriteFS struct{}
+
+func (realExWriteFS) Open(name string) (fs.File, error) { return stdos.Open(name) }
+
+func (realExWriteFS) Mkdir(name string, perm fs.FileMode) error {
+	return stdos.Mkdir(name, perm)
+}
+
+func (realExWriteFS) MkdirAll(name string, perm fs.FileMode) error {
+	return stdos.MkdirAll(name, perm)
+}
+
+func (realExWriteFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return stdos.WriteFile(name, data, perm)
+}
+
+func (realExWriteFS) Symlink(target, name string) error { return stdos.Symlink(target, name) }
+
+func (realExWriteFS) Chtimes(name string, atime, mtime time.Time) error {
+	return stdos.Chtimes(name, atime, mtime)
+}
+
+// Write writes FilesEx f into directory dir on the real OS filesystem,
+// creating new files, folders, and symlinks accordingly, honoring any Mode,
+// ModTime, and LinkTarget set on each File.
+//
+// See WriteFS.
+func (f FilesEx) Write(dir string) error {
+	return f.WriteFS(realExWriteFS{}, dir)
+}
+
+// WriteFS writes FilesEx f into directory dir of fsys, creating new files,
+// folders, and symlinks accordingly, honoring any Mode, ModTime, and
+// LinkTarget set on each File.
+//
+// A File with a zero Mode is written as 0600; a File with a zero ModTime is
+// left at whatever time WriteFS creates it. Since fsys must implement
+// ExWriteFS rather than the osa-mockable WriteFS, a caller on a mocked fs
+// gets a clear compile-time error instead of a write that silently mixes
+// the mock with the real OS filesystem.
+func (f FilesEx) WriteFS(fsys ExWriteFS, dir string) error {
+	for n, file := range f {
+		p := path.Join(dir, n)
+		d := path.Dir(p)
+		if err := fsys.MkdirAll(d, 0700); err != nil {
+			return err
+		}
+
+		if file.LinkTarget != "" {
+			if err := fsys.Symlink(file.LinkTarget, p); err != nil {
+				return err
+			}
+			continue
+		}
+
+		mode := file.Mode
+		if mode == 0 {
+			mode = 0600
+		}
+		if err := fsys.WriteFile(p, file.Data, mode); err != nil {
+			return err
+		}
+		if !file.ModTime.IsZero() {
+			if err := fsys.Chtimes(p, file.ModTime, file.ModTime); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
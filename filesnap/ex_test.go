@@ -0,0 +1,114 @@
+package filesnap_test
+
+import (
+	"io/fs"
+	stdos "os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/echocrow/fsnap/filesnap"
+	"github.com/echocrow/fsnap/memfs"
+	tos "github.com/echocrow/osa/testos"
+	"github.com/echocrow/osa/vos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadExScanFiles(t *testing.T) {
+	os, reset := vos.Patch()
+	defer reset()
+
+	tmpDir := tos.RequireTempDir(t, os)
+	tos.RequireMkdirAll(t, os, tos.Join(tmpDir, "some"))
+	tos.RequireWrite(t, os, tos.Join(tmpDir, "some", "nested.txt"), "File Contents")
+
+	got, err := filesnap.ReadEx(tmpDir, -1, filesnap.ReadOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("File Contents"), got["some/nested.txt"].Data)
+}
+
+func TestReadExOptions(t *testing.T) {
+	fsys := memfs.New()
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	fsys.MapFS["a.txt"] = &fstest.MapFile{
+		Data:    []byte("some contents"),
+		Mode:    0644,
+		ModTime: modTime,
+	}
+
+	t.Run("WithoutOpts", func(t *testing.T) {
+		got, err := filesnap.ReadExFS(fsys, ".", -1, filesnap.ReadOpts{})
+		require.NoError(t, err)
+		assert.Zero(t, got["a.txt"].Mode)
+		assert.Zero(t, got["a.txt"].ModTime)
+	})
+
+	t.Run("WithMode", func(t *testing.T) {
+		got, err := filesnap.ReadExFS(fsys, ".", -1, filesnap.ReadOpts{WithMode: true})
+		require.NoError(t, err)
+		assert.Equal(t, fs.FileMode(0644), got["a.txt"].Mode)
+	})
+
+	t.Run("WithModTime", func(t *testing.T) {
+		got, err := filesnap.ReadExFS(fsys, ".", -1, filesnap.ReadOpts{WithModTime: true})
+		require.NoError(t, err)
+		assert.True(t, modTime.Equal(got["a.txt"].ModTime))
+	})
+}
+
+func TestReadExSymlinkFallback(t *testing.T) {
+	fsys := memfs.New()
+	fsys.MapFS["link"] = &fstest.MapFile{
+		Data: []byte("target contents"),
+		Mode: fs.ModeSymlink,
+	}
+
+	got, err := filesnap.ReadExFS(fsys, ".", -1, filesnap.ReadOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("target contents"), got["link"].Data)
+	assert.Empty(t, got["link"].LinkTarget)
+}
+
+func TestWriteExModeModTimeAndSymlink(t *testing.T) {
+	// Symlinks and mtimes are written via the real OS filesystem directly
+	// (see FilesEx.Write), so this exercises a real temp dir instead of a
+	// vos-mocked one.
+	dir := t.TempDir()
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	f := filesnap.FilesEx{
+		"a.txt": {Data: []byte("contents"), Mode: 0640, ModTime: modTime},
+		"link":  {LinkTarget: "a.txt"},
+	}
+	require.NoError(t, f.Write(dir))
+
+	fi, err := stdos.Stat(filepath.Join(dir, "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0640), fi.Mode())
+	assert.WithinDuration(t, modTime, fi.ModTime(), time.Second)
+
+	target, err := stdos.Readlink(filepath.Join(dir, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "a.txt", target)
+}
+
+func TestWriteExScanFiles(t *testing.T) {
+	// FilesEx.Write always writes to the real OS filesystem (see
+	// ExWriteFS), so this uses a real temp dir rather than vos.Patch.
+	tmpDir := t.TempDir()
+
+	f := filesnap.FilesEx{
+		"myFile":      {Data: []byte("some contents")},
+		"nested/file": {Data: []byte("foobar")},
+	}
+	require.NoError(t, f.Write(tmpDir))
+
+	gotF, err := filesnap.Read(tmpDir, -1)
+	require.NoError(t, err)
+	assert.Equal(t, fsf{
+		"myFile":      []byte("some contents"),
+		"nested/file": []byte("foobar"),
+	}, gotF)
+}
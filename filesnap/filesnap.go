@@ -39,9 +39,15 @@
 package filesnap
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
 	"io/fs"
 	"path"
+	"sort"
 
 	os "github.com/echocrow/osa"
 )
@@ -58,21 +64,25 @@ type Files map[string][]byte
 //
 // If n >= 0, Read will descend at most n directory levels below the given
 // directory.
-func Read(dir string, n int) (Files, error) {
+func Read(dir string, n int, opts ...ReadOpt) (Files, error) {
 	osa := os.Current()
-	return ReadFS(osa, dir, n)
+	return ReadFS(osa, dir, n, opts...)
 }
 
 // ReadFS scans a fsys directory and returs its Files.
 //
 // See Read().
-func ReadFS(fsys fs.FS, dir string, n int) (Files, error) {
+func ReadFS(fsys fs.FS, dir string, n int, opts ...ReadOpt) (Files, error) {
+	var o readOpts
+	for _, opt := range opts {
+		opt(&o)
+	}
 	f := Files{}
-	err := f.readFS(fsys, dir, "", n)
+	err := f.readFS(fsys, dir, "", n, &o)
 	return f, err
 }
 
-func (f Files) readFS(fsys fs.FS, rootDir, subDir string, n int) error {
+func (f Files) readFS(fsys fs.FS, rootDir, subDir string, n int, o *readOpts) error {
 	dir := path.Join(rootDir, subDir)
 	entries, err := fs.ReadDir(fsys, dir)
 	if err != nil && err != io.EOF {
@@ -81,14 +91,23 @@ func (f Files) readFS(fsys fs.FS, rootDir, subDir string, n int) error {
 	for _, e := range entries {
 		p := path.Join(dir, e.Name())
 		sp := path.Join(subDir, e.Name())
+		if o.Select != nil && !o.Select(sp, e) {
+			continue
+		}
+		if o.isExcluded(sp) {
+			continue
+		}
 		if !e.IsDir() {
+			if !o.isIncluded(sp) {
+				continue
+			}
 			var err error
 			f[sp], err = fs.ReadFile(fsys, p)
 			if err != nil && err != io.EOF {
 				return err
 			}
 		} else if n != 0 {
-			if err := f.readFS(fsys, rootDir, sp, n-1); err != nil {
+			if err := f.readFS(fsys, rootDir, sp, n-1, o); err != nil {
 				return err
 			}
 		}
@@ -97,15 +116,81 @@ func (f Files) readFS(fsys fs.FS, rootDir, subDir string, n int) error {
 	return nil
 }
 
+// Hash returns a stable content hash for f, derived from the sha256 of each
+// file's contents keyed by its path.
+//
+// Two Files with identical paths and contents always produce the same hash,
+// independent of map iteration order.
+func (f Files) Hash() string {
+	names := make([]string, 0, len(f))
+	for n := range f {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, n := range names {
+		fh := sha256.Sum256(f[n])
+		io.WriteString(h, n)
+		h.Write(fh[:])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Diff compares two Files snapshots and returns the paths that were added,
+// removed, or changed between a and b.
+//
+// If a and b share the same Hash, Diff short-circuits and returns no
+// differences without inspecting individual files.
+func Diff(a, b Files) (added, removed, changed []string) {
+	if a.Hash() == b.Hash() {
+		return nil, nil, nil
+	}
+	for n, bd := range b {
+		ad, ok := a[n]
+		if !ok {
+			added = append(added, n)
+		} else if !bytes.Equal(ad, bd) {
+			changed = append(changed, n)
+		}
+	}
+	for n := range a {
+		if _, ok := b[n]; !ok {
+			removed = append(removed, n)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
+
 // Write writes Files f into directory dir, creating new files and folders
 // accordingly.
 //
-// Already existing colliding file will be overwritten.
-func (f Files) Write(dir string) error {
+// Already existing colliding file will be overwritten, unless
+// WriteOpts.Overwrite is set to false. If WriteOpts.Atomic is set, Write
+// stages each file via a temporary directory and os.Rename, and removes
+// every path it newly created if a later write fails. Files that already
+// existed and were overwritten before the failure keep their new contents.
+func (f Files) Write(dir string, opts ...WriteOpt) error {
+	o := newWriteOpts(opts)
+	if !o.Atomic {
+		return f.write(dir, o)
+	}
+	return f.writeAtomic(dir, o)
+}
+
+func (f Files) write(dir string, o WriteOpts) error {
 	for n, data := range f {
 		p := path.Join(dir, n)
-		dir := path.Dir(p)
-		if err := os.MkdirAll(dir, 0700); err != nil {
+		d := path.Dir(p)
+		if !o.Overwrite {
+			if _, err := os.Stat(p); err == nil {
+				return fmt.Errorf("filesnap: %s already exists", p)
+			}
+		}
+		if err := os.MkdirAll(d, 0700); err != nil {
 			return err
 		}
 		if err := os.WriteFile(p, data, 0600); err != nil {
@@ -114,3 +199,162 @@ func (f Files) Write(dir string) error {
 	}
 	return nil
 }
+
+// mkdirAllFrom creates dir and any missing parent directories one segment at
+// a time, appending every directory it newly creates to *created (shallowest
+// first) so a caller can roll them back individually if a later step fails.
+func mkdirAllFrom(dir string, created *[]string) error {
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if parent := path.Dir(dir); parent != dir {
+		if err := mkdirAllFrom(parent, created); err != nil {
+			return err
+		}
+	}
+	if err := os.Mkdir(dir, 0700); err != nil {
+		return err
+	}
+	if created != nil {
+		*created = append(*created, dir)
+	}
+	return nil
+}
+
+func (f Files) writeAtomic(dir string, o WriteOpts) error {
+	var created []string
+	rollback := func() {
+		for i := len(created) - 1; i >= 0; i-- {
+			_ = os.Remove(created[i])
+		}
+	}
+
+	for n, data := range f {
+		p := path.Join(dir, n)
+		d := path.Dir(p)
+
+		_, statErr := os.Stat(p)
+		existed := statErr == nil
+		if existed && !o.Overwrite {
+			rollback()
+			return fmt.Errorf("filesnap: %s already exists", p)
+		}
+		if err := mkdirAllFrom(d, &created); err != nil {
+			rollback()
+			return err
+		}
+
+		// osa has no CreateTemp, so stage the write in a temp dir and
+		// rename the single file it holds into place.
+		tmpDir, err := os.MkdirTemp(d, ".fsnap-*")
+		if err != nil {
+			rollback()
+			return err
+		}
+		tmpName := path.Join(tmpDir, "data")
+		if err := os.WriteFile(tmpName, data, 0600); err != nil {
+			_ = os.Remove(tmpDir)
+			rollback()
+			return err
+		}
+
+		if err := os.Rename(tmpName, p); err != nil {
+			_ = os.Remove(tmpName)
+			_ = os.Remove(tmpDir)
+			rollback()
+			return err
+		}
+		_ = os.Remove(tmpDir)
+
+		if !existed {
+			created = append(created, p)
+		}
+	}
+	return nil
+}
+
+// WriteFS is a writable fs.FS, extending it with the operations Write needs
+// to create files and folders.
+type WriteFS interface {
+	fs.FS
+	MkdirAll(string, fs.FileMode) error
+	WriteFile(string, []byte, fs.FileMode) error
+	Mkdir(string, fs.FileMode) error
+}
+
+// AtomicWriteFS is a WriteFS that can also remove entries, allowing WriteFS
+// to roll back a partially written tree when WriteOpts.Atomic is set.
+type AtomicWriteFS interface {
+	WriteFS
+	Remove(name string) error
+}
+
+// WriteFS writes Files f into directory dir of fsys, creating new files and
+// folders accordingly.
+//
+// See Write(). WriteOpts.Atomic requires fsys to implement AtomicWriteFS.
+func (f Files) WriteFS(fsys WriteFS, dir string, opts ...WriteOpt) error {
+	o := newWriteOpts(opts)
+	if !o.Atomic {
+		return f.writeFS(fsys, dir, o, nil)
+	}
+	afsys, ok := fsys.(AtomicWriteFS)
+	if !ok {
+		return errors.New("filesnap: fsys does not support atomic writes")
+	}
+	var created []string
+	if err := f.writeFS(fsys, dir, o, &created); err != nil {
+		for i := len(created) - 1; i >= 0; i-- {
+			_ = afsys.Remove(created[i])
+		}
+		return err
+	}
+	return nil
+}
+
+// mkdirAllFromFS is the WriteFS counterpart of mkdirAllFrom.
+func mkdirAllFromFS(fsys WriteFS, dir string, created *[]string) error {
+	if _, err := fs.Stat(fsys, dir); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if parent := path.Dir(dir); parent != dir {
+		if err := mkdirAllFromFS(fsys, parent, created); err != nil {
+			return err
+		}
+	}
+	if err := fsys.Mkdir(dir, 0700); err != nil {
+		return err
+	}
+	if created != nil {
+		*created = append(*created, dir)
+	}
+	return nil
+}
+
+func (f Files) writeFS(fsys WriteFS, dir string, o WriteOpts, created *[]string) error {
+	for n, data := range f {
+		p := path.Join(dir, n)
+		d := path.Dir(p)
+
+		_, statErr := fs.Stat(fsys, p)
+		existed := statErr == nil
+		if existed && !o.Overwrite {
+			return fmt.Errorf("filesnap: %s already exists", p)
+		}
+
+		if err := mkdirAllFromFS(fsys, d, created); err != nil {
+			return err
+		}
+		if err := fsys.WriteFile(p, data, 0600); err != nil {
+			return err
+		}
+		if created != nil && !existed {
+			*created = append(*created, p)
+		}
+	}
+	return nil
+}
@@ -1,10 +1,12 @@
 package filesnap_test
 
 import (
+	"io/fs"
 	"path"
 	"testing"
 
 	"github.com/echocrow/fsnap/filesnap"
+	"github.com/echocrow/fsnap/memfs"
 	"github.com/echocrow/osa"
 	tos "github.com/echocrow/osa/testos"
 	"github.com/echocrow/osa/vos"
@@ -92,7 +94,9 @@ func testScanFiles(
 func TestScanFiles(t *testing.T) {
 	os, reset := vos.Patch()
 	defer reset()
-	testScanFiles(t, os, filesnap.Read)
+	testScanFiles(t, os, func(name string, n int) (fsf, error) {
+		return filesnap.Read(name, n)
+	})
 }
 
 func TestScanFSFiles(t *testing.T) {
@@ -207,6 +211,206 @@ func TestWriteFiles(t *testing.T) {
 		assert.Error(t, err)
 		tos.AssertExistsIsDir(t, os, dirColl, false)
 	})
+
+	t.Run("ErrOverwriteDisabled", func(t *testing.T) {
+		tmpDir := tos.RequireTempDir(t, os)
+
+		path := tos.Join(tmpDir, "myFile")
+		tos.RequireWrite(t, os, path, "old contents")
+
+		f := fsf{"myFile": []byte("new contents")}
+		err := f.Write(tmpDir, filesnap.WithOverwrite(false))
+		assert.Error(t, err)
+		tos.AssertFileData(t, os, path, "old contents")
+	})
+
+	t.Run("Atomic", func(t *testing.T) {
+		t.Run("RollsBackOnError", func(t *testing.T) {
+			tmpDir := tos.RequireTempDir(t, os)
+
+			name := "myFile"
+			collPath := tos.Join(tmpDir, name)
+			tos.RequireMkdir(t, os, collPath)
+
+			f := fsf{
+				"first": []byte("first data"),
+				name:    []byte("some data"),
+			}
+			err := f.Write(tmpDir, filesnap.WithAtomic(true))
+			assert.Error(t, err)
+			tos.AssertExistsIsDir(t, os, collPath, true)
+
+			_, statErr := os.Stat(tos.Join(tmpDir, "first"))
+			assert.True(t, os.IsNotExist(statErr), "want rolled-back file to not exist")
+		})
+
+		t.Run("RollsBackNestedDirs", func(t *testing.T) {
+			tmpDir := tos.RequireTempDir(t, os)
+
+			name := "collide"
+			collPath := tos.Join(tmpDir, name)
+			tos.RequireMkdir(t, os, collPath)
+
+			f := fsf{
+				"newdir/sub/file.txt": []byte("nested data"),
+				name:                  []byte("some data"),
+			}
+			err := f.Write(tmpDir, filesnap.WithAtomic(true))
+			assert.Error(t, err)
+			tos.AssertExistsIsDir(t, os, collPath, true)
+
+			_, statErr := os.Stat(tos.Join(tmpDir, "newdir"))
+			assert.True(t, os.IsNotExist(statErr), "want rolled-back nested dir to not exist")
+		})
+
+		t.Run("Succeeds", func(t *testing.T) {
+			tmpDir := tos.RequireTempDir(t, os)
+
+			f := fsf{"myFile": []byte("some data")}
+			err := f.Write(tmpDir, filesnap.WithAtomic(true))
+			assert.NoError(t, err)
+			tos.AssertFileData(t, os, tos.Join(tmpDir, "myFile"), "some data")
+		})
+	})
+}
+
+func TestScanFilesFilter(t *testing.T) {
+	os, reset := vos.Patch()
+	defer reset()
+
+	tmpDir := tos.RequireTempDir(t, os)
+	tos.RequireWrite(t, os, tos.Join(tmpDir, "a.txt"), "a")
+	tos.RequireWrite(t, os, tos.Join(tmpDir, "a.log"), "a log")
+	tos.RequireMkdirAll(t, os, tos.Join(tmpDir, "node_modules"))
+	tos.RequireWrite(t, os, tos.Join(tmpDir, "node_modules", "dep.js"), "dep")
+	tos.RequireMkdirAll(t, os, tos.Join(tmpDir, "src"))
+	tos.RequireWrite(t, os, tos.Join(tmpDir, "src", "b.txt"), "b")
+
+	t.Run("Include", func(t *testing.T) {
+		got, err := filesnap.Read(tmpDir, -1, filesnap.WithInclude("**/*.txt"))
+		assert.NoError(t, err)
+		assert.Equal(t, fsf{
+			"a.txt":     []byte("a"),
+			"src/b.txt": []byte("b"),
+		}, got)
+	})
+
+	t.Run("Exclude", func(t *testing.T) {
+		got, err := filesnap.Read(tmpDir, -1, filesnap.WithExclude("node_modules/"))
+		assert.NoError(t, err)
+		assert.Equal(t, fsf{
+			"a.txt":     []byte("a"),
+			"a.log":     []byte("a log"),
+			"src/b.txt": []byte("b"),
+		}, got)
+	})
+
+	t.Run("Select", func(t *testing.T) {
+		sel := func(p string, d fs.DirEntry) bool {
+			return p != "src"
+		}
+		got, err := filesnap.Read(tmpDir, -1, filesnap.WithSelect(sel))
+		assert.NoError(t, err)
+		assert.Equal(t, fsf{
+			"a.txt":               []byte("a"),
+			"a.log":               []byte("a log"),
+			"node_modules/dep.js": []byte("dep"),
+		}, got)
+	})
+}
+
+func TestWriteFSFiles(t *testing.T) {
+	fsys := memfs.New()
+
+	f := fsf{
+		"myFile":      []byte("some contents"),
+		"nested/file": []byte("foobar"),
+	}
+
+	err := f.WriteFS(fsys, ".")
+	assert.NoError(t, err)
+
+	got, err := filesnap.ReadFS(fsys, ".", -1)
+	assert.NoError(t, err)
+	assert.Equal(t, f, got)
+}
+
+func TestWriteFSFilesAtomicRollsBackNestedDirs(t *testing.T) {
+	fsys := memfs.New()
+	name := "collide"
+	assert.NoError(t, fsys.WriteFile(name, []byte("existing"), 0600))
+
+	f := fsf{
+		"newdir/sub/file.txt": []byte("nested data"),
+		name:                  []byte("some data"),
+	}
+	err := f.WriteFS(fsys, ".", filesnap.WithAtomic(true), filesnap.WithOverwrite(false))
+	assert.Error(t, err)
+
+	_, statErr := fs.Stat(fsys, "newdir")
+	assert.True(t, osa.IsNotExist(statErr), "want rolled-back nested dir to not exist")
+}
+
+func TestFilesHash(t *testing.T) {
+	a := fsf{
+		"myFile":      []byte("some contents"),
+		"nested/file": []byte("foobar"),
+	}
+	b := fsf{
+		"nested/file": []byte("foobar"),
+		"myFile":      []byte("some contents"),
+	}
+	c := fsf{
+		"myFile":      []byte("other contents"),
+		"nested/file": []byte("foobar"),
+	}
+
+	assert.Equal(t, a.Hash(), b.Hash(), "want map order to not affect hash")
+	assert.NotEqual(t, a.Hash(), c.Hash(), "want differing contents to produce differing hashes")
+}
+
+func TestFilesDiff(t *testing.T) {
+	tests := []struct {
+		name        string
+		a, b        fsf
+		wantAdded   []string
+		wantRemoved []string
+		wantChanged []string
+	}{
+		{
+			"Equal",
+			fsf{"a": []byte("1")}, fsf{"a": []byte("1")},
+			nil, nil, nil,
+		},
+		{
+			"Added",
+			fsf{}, fsf{"a": []byte("1")},
+			[]string{"a"}, nil, nil,
+		},
+		{
+			"Removed",
+			fsf{"a": []byte("1")}, fsf{},
+			nil, []string{"a"}, nil,
+		},
+		{
+			"Changed",
+			fsf{"a": []byte("1")}, fsf{"a": []byte("2")},
+			nil, nil, []string{"a"},
+		},
+		{
+			"Mixed",
+			fsf{"a": []byte("1"), "b": []byte("1")}, fsf{"b": []byte("2"), "c": []byte("1")},
+			[]string{"c"}, []string{"a"}, []string{"b"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			added, removed, changed := filesnap.Diff(tc.a, tc.b)
+			assert.Equal(t, tc.wantAdded, added, "added")
+			assert.Equal(t, tc.wantRemoved, removed, "removed")
+			assert.Equal(t, tc.wantChanged, changed, "changed")
+		})
+	}
 }
 
 func TestWriteFilesScanFiles(t *testing.T) {
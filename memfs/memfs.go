@@ -0,0 +1,96 @@
+// Package memfs provides a small in-memory filesystem that satisfies
+// filesnap.WriteFS and dirsnap.WriteFS, letting callers round-trip snapshots
+// without touching disk.
+//
+// Here is an example of how to use this package during testing:
+//
+//	import (
+//		"testing"
+//
+//		"github.com/echocrow/fsnap/filesnap"
+//		"github.com/echocrow/fsnap/memfs"
+//		"github.com/stretchr/testify/require"
+//	)
+//
+//	func TestMyFilesConcat(t *testing.T) {
+//		fsys := memfs.New()
+//
+//		require.NoError(t, filesnap.Files{
+//			"a_0.txt": []byte("prefix"),
+//			"a_1.txt": []byte("suffix"),
+//		}.WriteFS(fsys, "."))
+//
+//		got, err := filesnap.ReadFS(fsys, ".", -1)
+//		require.NoError(t, err)
+//		_ = got
+//	}
+package memfs
+
+import (
+	"io/fs"
+	"path"
+	"testing/fstest"
+)
+
+// FS is an in-memory, writable filesystem. Its zero value is not usable; use
+// New to construct one.
+type FS struct {
+	fstest.MapFS
+}
+
+// New returns an empty, ready to use FS.
+func New() *FS {
+	return &FS{MapFS: fstest.MapFS{}}
+}
+
+// MkdirAll creates dir and any missing parents as directory entries.
+func (f *FS) MkdirAll(dir string, mode fs.FileMode) error {
+	dir = path.Clean(dir)
+	for d := dir; d != "." && d != "/"; d = path.Dir(d) {
+		if _, ok := f.MapFS[d]; ok {
+			continue
+		}
+		f.MapFS[d] = &fstest.MapFile{Mode: fs.ModeDir | mode}
+	}
+	return nil
+}
+
+// Mkdir creates a single directory entry, failing if it already exists.
+func (f *FS) Mkdir(dir string, mode fs.FileMode) error {
+	dir = path.Clean(dir)
+	if _, err := fs.Stat(f.MapFS, dir); err == nil {
+		return fs.ErrExist
+	}
+	f.MapFS[dir] = &fstest.MapFile{Mode: fs.ModeDir | mode}
+	return nil
+}
+
+// WriteFile creates or overwrites the file at name with data.
+func (f *FS) WriteFile(name string, data []byte, mode fs.FileMode) error {
+	f.MapFS[path.Clean(name)] = &fstest.MapFile{Data: data, Mode: mode}
+	return nil
+}
+
+// Remove removes the entry at name.
+func (f *FS) Remove(name string) error {
+	name = path.Clean(name)
+	if _, ok := f.MapFS[name]; !ok {
+		return fs.ErrNotExist
+	}
+	delete(f.MapFS, name)
+	return nil
+}
+
+// RemoveAll removes the entry at name and, if it is a directory, every
+// entry nested below it.
+func (f *FS) RemoveAll(name string) error {
+	name = path.Clean(name)
+	delete(f.MapFS, name)
+	prefix := name + "/"
+	for n := range f.MapFS {
+		if len(n) > len(prefix) && n[:len(prefix)] == prefix {
+			delete(f.MapFS, n)
+		}
+	}
+	return nil
+}
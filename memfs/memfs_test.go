@@ -0,0 +1,66 @@
+package memfs_test
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/echocrow/fsnap/filesnap"
+	"github.com/echocrow/fsnap/memfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMkdirAll(t *testing.T) {
+	fsys := memfs.New()
+
+	err := fsys.MkdirAll("a/b/c", 0700)
+	require.NoError(t, err)
+
+	for _, n := range []string{"a", "a/b", "a/b/c"} {
+		fi, err := fs.Stat(fsys, n)
+		require.NoError(t, err)
+		assert.True(t, fi.IsDir(), "want %s to be a directory", n)
+	}
+}
+
+func TestMkdir(t *testing.T) {
+	fsys := memfs.New()
+
+	require.NoError(t, fsys.Mkdir("a", 0700))
+	assert.ErrorIs(t, fsys.Mkdir("a", 0700), fs.ErrExist)
+}
+
+func TestWriteFile(t *testing.T) {
+	fsys := memfs.New()
+
+	require.NoError(t, fsys.WriteFile("a/b", []byte("data"), 0600))
+
+	got, err := fs.ReadFile(fsys, "a/b")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("data"), got)
+}
+
+func TestRemoveAll(t *testing.T) {
+	fsys := memfs.New()
+	require.NoError(t, fsys.MkdirAll("a/b", 0700))
+	require.NoError(t, fsys.WriteFile("a/b/c", []byte("data"), 0600))
+
+	require.NoError(t, fsys.RemoveAll("a"))
+
+	_, err := fs.Stat(fsys, "a/b/c")
+	assert.Error(t, err, "want a to be fully removed")
+}
+
+func TestWriteFSRoundTrip(t *testing.T) {
+	fsys := memfs.New()
+
+	f := filesnap.Files{
+		"myFile":      []byte("some contents"),
+		"nested/file": []byte("foobar"),
+	}
+	require.NoError(t, f.WriteFS(fsys, "."))
+
+	got, err := filesnap.ReadFS(fsys, ".", -1)
+	require.NoError(t, err)
+	assert.Equal(t, f, got)
+}